@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/apis/duck"
+)
+
+// Authenticatable is the schema for the authenticatable portion of the
+// payload. It describes the identity (service account and, optionally, the
+// audiences it is allowed to mint tokens for) that a target runs as, so
+// that other controllers can discover "who" a target is.
+type Authenticatable struct {
+	// ServiceAccountName is the name of the Kubernetes service account the
+	// target runs as.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Audiences is the list of intended audiences for tokens issued on
+	// behalf of the target's service account. This field is optional.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+// Implementations can verify that they implement Authenticatable via:
+var _ = duck.VerifyType(&AuthTarget{}, &Authenticatable{})
+
+// Authenticatable is an Implementable "duck type".
+var _ duck.Implementable = (*Authenticatable)(nil)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AuthTarget is a skeleton type wrapping Authenticatable in the manner we
+// expect resource writers defining compatible resources to embed it.  We
+// will typically use this type to deserialize Authenticatable
+// ObjectReferences and access the Authenticatable data. This is not a real
+// resource.
+type AuthTarget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status AuthTargetStatus `json:"status"`
+}
+
+// AuthTargetStatus shows how we expect folks to embed Authenticatable in
+// their Status field.
+type AuthTargetStatus struct {
+	Auth *Authenticatable `json:"auth,omitempty"`
+}
+
+// In order for Authenticatable to be Implementable, AuthTarget must be Populatable.
+var _ duck.Populatable = (*AuthTarget)(nil)
+
+// GetFullType implements duck.Implementable
+func (_ *Authenticatable) GetFullType() duck.Populatable {
+	return &AuthTarget{}
+}
+
+// Populate implements duck.Populatable
+func (t *AuthTarget) Populate() {
+	t.Status.Auth = &Authenticatable{
+		// Populate ALL fields
+		ServiceAccountName: "this-is-not-empty",
+		Audiences:          []string{"this-is-not-empty"},
+	}
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AuthTargetList is a list of AuthTarget resources
+type AuthTargetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AuthTarget `json:"items"`
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"knative.dev/pkg/apis"
+
+	duckv1 "knative.dev/serving/pkg/apis/duck/v1"
+)
+
+// ConvertTo implements apis.Convertible
+func (source *Target) ConvertTo(ctx context.Context, obj apis.Convertible) error {
+	switch sink := obj.(type) {
+	case *duckv1.Target:
+		sink.ObjectMeta = source.ObjectMeta
+		sink.Status = duckv1.TargetStatus{}
+		if source.Status.Targettable != nil {
+			sink.Status.Targettable = &duckv1.Targettable{
+				Field: source.Status.Targettable.Field,
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown conversion, got: %T", sink)
+	}
+}
+
+// ConvertFrom implements apis.Convertible
+func (sink *Target) ConvertFrom(ctx context.Context, obj apis.Convertible) error {
+	switch source := obj.(type) {
+	case *duckv1.Target:
+		sink.ObjectMeta = source.ObjectMeta
+		sink.Status = TargetStatus{}
+		if source.Status.Targettable != nil {
+			sink.Status.Targettable = &Targettable{
+				Field: source.Status.Targettable.Field,
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown conversion, got: %T", source)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	duckv1 "knative.dev/serving/pkg/apis/duck/v1"
+)
+
+// TestTargetConversionRoundTrip fuzzes the name, namespace and Targettable
+// field of a Target (with and without a Targettable) and asserts that
+// converting v1alpha1 -> v1 -> v1alpha1 always yields back the original
+// value. Fields other than these are never set by the test, so a field
+// added later is only covered once it is generated here too.
+func TestTargetConversionRoundTrip(t *testing.T) {
+	roundTrip := func(name, namespace, field string, hasTargettable bool) bool {
+		in := &Target{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}
+		if hasTargettable {
+			in.Status.Targettable = &Targettable{Field: field}
+		}
+
+		ctx := context.Background()
+
+		mid := &duckv1.Target{}
+		if err := in.ConvertTo(ctx, mid); err != nil {
+			t.Logf("ConvertTo() = %v", err)
+			return false
+		}
+
+		out := &Target{}
+		if err := out.ConvertFrom(ctx, mid); err != nil {
+			t.Logf("ConvertFrom() = %v", err)
+			return false
+		}
+
+		return reflect.DeepEqual(in, out)
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 256}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestTargetConvertToUnknownType checks that ConvertTo rejects destination
+// types it doesn't know how to populate, rather than silently no-opping.
+func TestTargetConvertToUnknownType(t *testing.T) {
+	src := &Target{}
+	if err := src.ConvertTo(context.Background(), &Target{}); err == nil {
+		t.Error("ConvertTo() with an unsupported sink = nil, wanted an error")
+	}
+}
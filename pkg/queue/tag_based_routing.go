@@ -1,23 +1,90 @@
 package queue
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
 
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
 
 	pkgmetrics "knative.dev/pkg/metrics"
 	"knative.dev/serving/pkg/metrics"
 	"knative.dev/serving/pkg/network"
 )
 
+// RoutingMode controls how NewTagBasedRoutingHandler reacts to a request
+// whose Knative-Serving-Tag header doesn't match the Knative-Serving-Tag-Ref
+// header.
+type RoutingMode int
+
+const (
+	// ModeStrict rejects mismatched requests with 404/502, as the handler
+	// has always done.
+	ModeStrict RoutingMode = iota
+	// ModeFallback lets mismatched requests through to next unchanged.
+	ModeFallback
+	// ModeMirror lets mismatched requests through to next for the
+	// user-visible response, while asynchronously mirroring a copy of the
+	// request to a shadow backend derived from the mismatched tag.
+	ModeMirror
+	// ModeWeighted picks a tag for requests that arrive with no tag header,
+	// using a weighted, sticky hash of a stable request attribute, so that
+	// traffic can be shifted across tags progressively.
+	ModeWeighted
+)
+
+const (
+	// mirrorWorkerCount bounds the number of goroutines dispatching
+	// mirrored requests, so a slow shadow backend can't pile up unbounded
+	// work.
+	mirrorWorkerCount = 4
+	// mirrorQueueDepth bounds the number of mirror jobs awaiting a worker.
+	// Jobs submitted once the queue is full are dropped, not blocked on.
+	mirrorQueueDepth = 64
+	// mirrorRequestTimeout bounds how long a single mirrored request may
+	// take. Without it, a hung shadow backend parks a worker (and its
+	// connection) forever, degrading the mirror pool to fully dropped
+	// after one slow-backend incident.
+	mirrorRequestTimeout = 5 * time.Second
+)
+
 var (
 	requestWithInvalidTagHeaderCountM = stats.Int64(
 		"request_with_invalid_tag_header_count",
 		"The number of requests with the tag header which is not matched with the tag reference header",
 		stats.UnitDimensionless)
+
+	requestMirroredCountM = stats.Int64(
+		"request_mirrored_count",
+		"The number of requests mirrored to a shadow backend due to a tag mismatch",
+		stats.UnitDimensionless)
+
+	requestMirrorLatencyM = stats.Float64(
+		"request_mirror_latency",
+		"The time spent waiting for a mirrored request's response",
+		stats.UnitMilliseconds)
+
+	requestMirrorDroppedCountM = stats.Int64(
+		"request_mirror_dropped_count",
+		"The number of mirrored requests dropped because the mirror worker pool was saturated",
+		stats.UnitDimensionless)
+
+	requestMirrorStatusCodeM = stats.Int64(
+		"request_mirror_status_code",
+		"The HTTP status code returned by the shadow backend for a mirrored request",
+		stats.UnitDimensionless)
+
+	tagRoutingDecisionCountM = stats.Int64(
+		"tag_routing_decision_count",
+		"The number of requests assigned a tag by the weighted tag routing mode",
+		stats.UnitDimensionless)
 )
 
 func getUniqueHeader(r *http.Request, headerName string) (string, error) {
@@ -29,26 +96,143 @@ func getUniqueHeader(r *http.Request, headerName string) (string, error) {
 	return r.Header.Get(headerName), nil
 }
 
+// mirrorJob is the unit of work handed to the mirror worker pool.
+type mirrorJob struct {
+	req      *http.Request
+	statsCtx context.Context
+}
+
+// mirrorDispatcher asynchronously replays requests against a shadow backend
+// on a bounded pool of workers, dropping (and counting) work that arrives
+// faster than the pool can keep up.
+type mirrorDispatcher struct {
+	shadowBackends map[string]*url.URL
+	jobs           chan mirrorJob
+	client         *http.Client
+}
+
+func newMirrorDispatcher(shadowBackends map[string]*url.URL) *mirrorDispatcher {
+	d := &mirrorDispatcher{
+		shadowBackends: shadowBackends,
+		jobs:           make(chan mirrorJob, mirrorQueueDepth),
+		client:         &http.Client{Timeout: mirrorRequestTimeout},
+	}
+	for i := 0; i < mirrorWorkerCount; i++ {
+		go d.work()
+	}
+	return d
+}
+
+func (d *mirrorDispatcher) work() {
+	for job := range d.jobs {
+		start := time.Now()
+		resp, err := d.client.Do(job.req)
+		latencyMs := float64(time.Since(start)) / float64(time.Millisecond)
+
+		measurements := []stats.Measurement{
+			requestMirroredCountM.M(1),
+			requestMirrorLatencyM.M(latencyMs),
+		}
+		if err == nil {
+			// The mirrored response body is discarded; only its status code
+			// and the latency to receive it are recorded.
+			measurements = append(measurements, requestMirrorStatusCodeM.M(int64(resp.StatusCode)))
+			resp.Body.Close()
+		}
+
+		if job.statsCtx != nil {
+			pkgmetrics.RecordBatch(job.statsCtx, measurements...)
+		}
+	}
+}
+
+// dispatch clones req for the shadow backend registered for tag and enqueues
+// it for asynchronous replay. If the worker pool is saturated, the job is
+// dropped and counted rather than blocking the caller.
+func (d *mirrorDispatcher) dispatch(req *http.Request, body []byte, tag string, statsCtx context.Context) {
+	target, ok := d.shadowBackends[tag]
+	if !ok {
+		return
+	}
+
+	mirrorURL := *target
+	mirrorURL.Path = req.URL.Path
+	mirrorURL.RawQuery = req.URL.RawQuery
+
+	mirrorReq, err := http.NewRequest(req.Method, mirrorURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	mirrorReq.Header = req.Header.Clone()
+	mirrorReq.Header.Set("X-Mirror", "1")
+
+	select {
+	case d.jobs <- mirrorJob{req: mirrorReq, statsCtx: statsCtx}:
+	default:
+		if statsCtx != nil {
+			pkgmetrics.RecordBatch(statsCtx, requestMirrorDroppedCountM.M(1))
+		}
+	}
+}
+
 // NewTagBasedRoutingHandler create a handler for detecting inconsistency between the tag header coming with a request and the reference tag header denoting the route defined in Ingress
-func NewTagBasedRoutingHandler(next http.Handler, ns string, service string, config string, rev string, enableFallback bool) http.Handler {
+func NewTagBasedRoutingHandler(next http.Handler, ns string, service string, config string, rev string, routingMode RoutingMode, shadowBackends map[string]*url.URL, tagWeights *atomic.Value) http.Handler {
 	keys := append(metrics.CommonRevisionKeys,
 		metrics.TagActualKey,
 		metrics.TagExpectedKey)
 
 	var statsCtx context.Context
 
-	if err := view.Register(&view.View{
-		Description: requestWithInvalidTagHeaderCountM.Description(),
-		Measure:     requestWithInvalidTagHeaderCountM,
-		Aggregation: view.Count(),
-		TagKeys:     keys,
-	}); err != nil {
+	if err := view.Register(
+		&view.View{
+			Description: requestWithInvalidTagHeaderCountM.Description(),
+			Measure:     requestWithInvalidTagHeaderCountM,
+			Aggregation: view.Count(),
+			TagKeys:     keys,
+		},
+		&view.View{
+			Description: requestMirroredCountM.Description(),
+			Measure:     requestMirroredCountM,
+			Aggregation: view.Count(),
+			TagKeys:     keys,
+		},
+		&view.View{
+			Description: requestMirrorLatencyM.Description(),
+			Measure:     requestMirrorLatencyM,
+			Aggregation: view.Distribution(pkgmetrics.Buckets125(1, 10000)...),
+			TagKeys:     keys,
+		},
+		&view.View{
+			Description: requestMirrorDroppedCountM.Description(),
+			Measure:     requestMirrorDroppedCountM,
+			Aggregation: view.Count(),
+			TagKeys:     keys,
+		},
+		&view.View{
+			Description: requestMirrorStatusCodeM.Description(),
+			Measure:     requestMirrorStatusCodeM,
+			Aggregation: view.LastValue(),
+			TagKeys:     keys,
+		},
+		&view.View{
+			Description: tagRoutingDecisionCountM.Description(),
+			Measure:     tagRoutingDecisionCountM,
+			Aggregation: view.Count(),
+			TagKeys:     keys,
+		},
+	); err != nil {
 		statsCtx = nil
 	}
 	statsCtx, err := metrics.RevisionContext(ns, service, config, rev)
 	if err != nil {
 		statsCtx = nil
 	}
+
+	var dispatcher *mirrorDispatcher
+	if routingMode == ModeMirror {
+		dispatcher = newMirrorDispatcher(shadowBackends)
+	}
+
 	// To prevent use of appended
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// uniqueness check
@@ -63,11 +247,52 @@ func NewTagBasedRoutingHandler(next http.Handler, ns string, service string, con
 			return
 		}
 
+		ctx, span := trace.StartSpan(r.Context(), "queue_proxy.tag_routing")
+		fallbackEnabled := routingMode == ModeFallback || routingMode == ModeMirror
+		span.AddAttributes(
+			trace.StringAttribute("knative.tag.actual", tag),
+			trace.StringAttribute("knative.tag.expected", tagRef),
+			trace.BoolAttribute("knative.tag.fallback_enabled", fallbackEnabled),
+		)
+		r = r.WithContext(ctx)
+		defer span.End()
+
+		if routingMode == ModeWeighted && tag == "" {
+			if chosen := pickWeightedTag(loadTagWeights(tagWeights), stickyKey(r)); chosen != "" {
+				r.Header.Set(network.TagHeaderName, chosen)
+				tag = chosen
+
+				if statsCtx != nil {
+					ctx := metrics.AugmentWithActualAndExpectedTagName(statsCtx, chosen, chosen)
+					pkgmetrics.RecordBatch(ctx, tagRoutingDecisionCountM.M(1))
+				}
+			}
+		}
+
 		defer func() {
 			w.Header().Add(network.TagRefHeaderName, tagRef)
 		}()
 
-		if !enableFallback && len(tag) > 0 && tag != tagRef {
+		mismatched := len(tag) > 0 && tag != tagRef
+
+		if mismatched && routingMode == ModeMirror && dispatcher != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			// Mirroring is best-effort and must never be able to corrupt the
+			// primary, user-visible request: always give next.ServeHTTP a
+			// fresh body, whether or not the read above succeeded.
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if err == nil {
+				var ctx context.Context
+				if statsCtx != nil {
+					ctx = metrics.AugmentWithActualAndExpectedTagName(statsCtx, GetTagRefName(r), GetTagName(r))
+				}
+				dispatcher.dispatch(r, body, tag, ctx)
+			}
+		}
+
+		if routingMode == ModeStrict && mismatched {
 			if statsCtx != nil {
 				ctx := metrics.AugmentWithActualAndExpectedTagName(statsCtx, GetTagRefName(r), GetTagName(r))
 				pkgmetrics.RecordBatch(ctx, requestWithInvalidTagHeaderCountM.M(1))
@@ -76,13 +301,18 @@ func NewTagBasedRoutingHandler(next http.Handler, ns string, service string, con
 			if tagRef == network.DefaultTargetHeaderValue {
 				// If a request has different values on tag and tagrRef, it is an invalid request.
 				// Since such case happen when a user make a request with non-existing tag, here, NotFound is returned.
+				span.AddAttributes(trace.StringAttribute("tag.decision", "rejected-notfound"))
+				span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: "tag not found"})
 				http.Error(w, "tag not found", http.StatusNotFound)
 			} else {
+				span.AddAttributes(trace.StringAttribute("tag.decision", "rejected-badgateway"))
+				span.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: "inconsistent tag is provided"})
 				http.Error(w, "inconsistent tag is provided", http.StatusBadGateway)
 			}
 			return
 		}
 
+		span.AddAttributes(trace.StringAttribute("tag.decision", "passthrough"))
 		next.ServeHTTP(w, r)
 	})
 }
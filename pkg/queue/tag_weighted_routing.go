@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+)
+
+// sessionIDHeaderName is the request-derived, stable attribute used to
+// stick a caller to the same weighted tag across requests (and across
+// queue-proxy restarts, since it never depends on in-memory state).
+const sessionIDHeaderName = "X-Session-ID"
+
+// weightedRange is a half-open [from, to) bucket of the [0, 100) hash space
+// assigned to a tag.
+type weightedRange struct {
+	tag  string
+	from int
+	to   int
+}
+
+// buildWeightedRanges turns a tag -> weight map into a deterministic,
+// sorted set of cumulative ranges over [0, 100). Tags are ordered by name so
+// that the same weights always produce the same ranges, regardless of map
+// iteration order.
+func buildWeightedRanges(tagWeights map[string]int) []weightedRange {
+	tags := make([]string, 0, len(tagWeights))
+	for tag := range tagWeights {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	ranges := make([]weightedRange, 0, len(tags))
+	cursor := 0
+	for _, tag := range tags {
+		w := tagWeights[tag]
+		if w <= 0 {
+			continue
+		}
+		ranges = append(ranges, weightedRange{tag: tag, from: cursor, to: cursor + w})
+		cursor += w
+	}
+	return ranges
+}
+
+// hashBucket deterministically maps key into [0, 100).
+func hashBucket(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// pickWeightedTag chooses a tag for key (stable request-derived entropy)
+// among the cumulative ranges built from tagWeights. Returns "" if
+// tagWeights is empty or sums to zero.
+func pickWeightedTag(tagWeights map[string]int, key string) string {
+	ranges := buildWeightedRanges(tagWeights)
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	bucket := hashBucket(key)
+	for _, rg := range ranges {
+		if bucket >= rg.from && bucket < rg.to {
+			return rg.tag
+		}
+	}
+	// Weights summing to less than 100 leave a remainder bucket; fall back
+	// to the last tag rather than leaving the request untagged.
+	return ranges[len(ranges)-1].tag
+}
+
+// stickyKey returns the stable, request-derived attribute used to compute
+// the weighted routing decision: the session header if present, otherwise
+// the client IP.
+func stickyKey(r *http.Request) string {
+	if session := r.Header.Get(sessionIDHeaderName); session != "" {
+		return session
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	// RemoteAddr had no port (e.g. a unix socket or a non-standard dialer);
+	// use it as-is rather than mis-splitting it.
+	return r.RemoteAddr
+}
+
+// loadTagWeights reads the live tag weight map out of an atomic.Value,
+// tolerating a nil or not-yet-populated value.
+func loadTagWeights(weights *atomic.Value) map[string]int {
+	if weights == nil {
+		return nil
+	}
+	v := weights.Load()
+	if v == nil {
+		return nil
+	}
+	return v.(map[string]int)
+}
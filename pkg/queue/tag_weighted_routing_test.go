@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBuildWeightedRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		tagWeights map[string]int
+		want       []weightedRange
+	}{{
+		name:       "even split",
+		tagWeights: map[string]int{"blue": 50, "green": 50},
+		want: []weightedRange{
+			{tag: "blue", from: 0, to: 50},
+			{tag: "green", from: 50, to: 100},
+		},
+	}, {
+		name:       "zero weight tags are skipped",
+		tagWeights: map[string]int{"blue": 100, "dark": 0},
+		want: []weightedRange{
+			{tag: "blue", from: 0, to: 100},
+		},
+	}, {
+		name:       "uneven split keeps boundaries exact",
+		tagWeights: map[string]int{"blue": 10, "green": 30, "red": 60},
+		want: []weightedRange{
+			{tag: "blue", from: 0, to: 10},
+			{tag: "green", from: 10, to: 40},
+			{tag: "red", from: 40, to: 100},
+		},
+	}, {
+		name:       "empty",
+		tagWeights: map[string]int{},
+		want:       []weightedRange{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := buildWeightedRanges(test.tagWeights)
+			if len(got) != len(test.want) {
+				t.Fatalf("buildWeightedRanges() = %+v, want %+v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("range[%d] = %+v, want %+v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPickWeightedTag(t *testing.T) {
+	tagWeights := map[string]int{"blue": 50, "green": 50}
+
+	if got := pickWeightedTag(map[string]int{}, "any-key"); got != "" {
+		t.Errorf("pickWeightedTag() with no weights = %q, want empty", got)
+	}
+
+	// The same key must always resolve to the same tag (sticky routing).
+	first := pickWeightedTag(tagWeights, "session-a")
+	for i := 0; i < 10; i++ {
+		if got := pickWeightedTag(tagWeights, "session-a"); got != first {
+			t.Errorf("pickWeightedTag() not sticky: got %q, want %q", got, first)
+		}
+	}
+
+	// Weights summing to less than 100 must still resolve every bucket to a
+	// known tag instead of leaving requests untagged.
+	partial := map[string]int{"blue": 40}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if got := pickWeightedTag(partial, key); got != "blue" {
+			t.Errorf("pickWeightedTag(partial, %q) = %q, want blue", key, got)
+		}
+	}
+}
+
+func TestStickyKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		want       string
+	}{{
+		name:       "session header wins",
+		header:     "session-123",
+		remoteAddr: "10.0.0.1:54321",
+		want:       "session-123",
+	}, {
+		name:       "ipv4 fallback strips port",
+		remoteAddr: "10.0.0.1:54321",
+		want:       "10.0.0.1",
+	}, {
+		name:       "ipv6 fallback strips port without truncating the address",
+		remoteAddr: "[2001:db8::1]:54321",
+		want:       "2001:db8::1",
+	}, {
+		name:       "no port falls back to the raw address",
+		remoteAddr: "unix-socket",
+		want:       "unix-socket",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.header != "" {
+				r.Header.Set(sessionIDHeaderName, test.header)
+			}
+			if got := stickyKey(r); got != test.want {
+				t.Errorf("stickyKey() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadTagWeights(t *testing.T) {
+	if got := loadTagWeights(nil); got != nil {
+		t.Errorf("loadTagWeights(nil) = %v, want nil", got)
+	}
+
+	var v atomic.Value
+	if got := loadTagWeights(&v); got != nil {
+		t.Errorf("loadTagWeights() on an unset atomic.Value = %v, want nil", got)
+	}
+
+	want := map[string]int{"blue": 100}
+	v.Store(want)
+	if got := loadTagWeights(&v); got["blue"] != want["blue"] {
+		t.Errorf("loadTagWeights() = %v, want %v", got, want)
+	}
+}